@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package addons renders the optional, templated manifests (dashboard,
+// storage-provisioner, metrics-server, ...) p8s can enable on top of the
+// core control-plane, either as static pods picked up by the kubelet or as
+// objects applied through the Kubernetes API once it's reachable.
+package addons
+
+// Mode describes how a rendered resource reaches the cluster.
+type Mode string
+
+const (
+	// ModeStaticPod writes the rendered manifest into the kubelet's
+	// static-pod directory, the same way the core control-plane pods are
+	// started. Only Pod objects are valid in this mode.
+	ModeStaticPod Mode = "static-pod"
+	// ModeApply queues the rendered manifest to be applied through the
+	// Kubernetes API once the apiserver is ready.
+	ModeApply Mode = "apply"
+)
+
+// Resource is a single templated manifest delivered as part of an Addon.
+// An addon bundling both a static pod and API objects (e.g. a provisioner
+// pod plus its StorageClass) declares one Resource per object, each with
+// its own Mode.
+type Resource struct {
+	// Mode selects how this resource is delivered
+	Mode Mode
+	// TemplateRelPath is the path of the manifest template, relative to
+	// the addons source-templates directory
+	TemplateRelPath string
+	// FileName is the name given to the rendered manifest on disk
+	FileName string
+}
+
+// Addon describes a single pluggable addon, made of one or more Resources.
+type Addon struct {
+	// Name is the identifier used in --addons and in EnableAddon/DisableAddon
+	Name string
+	// Resources are rendered and delivered together when the addon is
+	// enabled, and removed together when it's disabled
+	Resources []Resource
+}
+
+// All is the catalog of addons p8s knows how to render. Manager.SetupEnabled
+// doesn't follow this order: enabled addons are rendered in map order.
+var All = []*Addon{
+	{
+		Name: "dashboard",
+		Resources: []Resource{
+			{Mode: ModeStaticPod, TemplateRelPath: "kubernetes-dashboard/manifest.yaml.tmpl", FileName: "addon-kubernetes-dashboard.yaml"},
+		},
+	},
+	{
+		Name: "storage-provisioner",
+		Resources: []Resource{
+			{Mode: ModeStaticPod, TemplateRelPath: "storage-provisioner/pod.yaml.tmpl", FileName: "addon-storage-provisioner.yaml"},
+			{Mode: ModeApply, TemplateRelPath: "storage-provisioner/storageclass.yaml.tmpl", FileName: "addon-storage-provisioner-storageclass.yaml"},
+		},
+	},
+	{
+		Name: "metrics-server",
+		Resources: []Resource{
+			{Mode: ModeApply, TemplateRelPath: "metrics-server/manifest.yaml.tmpl", FileName: "addon-metrics-server.yaml"},
+		},
+	},
+	{
+		Name: "coredns-override",
+		Resources: []Resource{
+			{Mode: ModeApply, TemplateRelPath: "coredns-override/manifest.yaml.tmpl", FileName: "addon-coredns-override.yaml"},
+		},
+	},
+}
+
+// Get returns the addon registered under name, if any.
+func Get(name string) (*Addon, bool) {
+	for _, a := range All {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return nil, false
+}
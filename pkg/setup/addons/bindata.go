@@ -0,0 +1,177 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package addons
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+// sourceTemplates embeds the manifest.yaml.tmpl assets under
+// source-templates/<name>/, keyed by Addon.TemplateRelPath, so the binary
+// doesn't depend on the source tree being present at runtime.
+var sourceTemplates = map[string]string{
+	"kubernetes-dashboard/manifest.yaml.tmpl":    kubernetesDashboardTemplate,
+	"storage-provisioner/pod.yaml.tmpl":          storageProvisionerPodTemplate,
+	"storage-provisioner/storageclass.yaml.tmpl": storageProvisionerStorageClassTemplate,
+	"metrics-server/manifest.yaml.tmpl":          metricsServerTemplate,
+	"coredns-override/manifest.yaml.tmpl":        corednsOverrideTemplate,
+}
+
+// WriteSourceTemplates materializes every known addon template under
+// destABSPath/<TemplateRelPath>, creating parent directories as needed.
+func WriteSourceTemplates(destABSPath string) error {
+	for relPath, content := range sourceTemplates {
+		dest := path.Join(destABSPath, relPath)
+		if err := os.MkdirAll(path.Dir(dest), os.ModePerm); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(dest, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const kubernetesDashboardTemplate = `apiVersion: v1
+kind: Pod
+metadata:
+  name: kubernetes-dashboard
+  namespace: kube-system
+  labels:
+    k8s-app: kubernetes-dashboard
+    p8s-addon: kubernetes-dashboard
+spec:
+  hostNetwork: true
+  containers:
+  - name: kubernetes-dashboard
+    image: k8s.gcr.io/kubernetes-dashboard-amd64:v1.10.1
+    ports:
+    - containerPort: 8443
+      protocol: TCP
+    args:
+    - --auto-generate-certificates
+    - --authentication-mode=token
+`
+
+const storageProvisionerPodTemplate = `apiVersion: v1
+kind: Pod
+metadata:
+  name: storage-provisioner
+  namespace: kube-system
+  labels:
+    k8s-app: storage-provisioner
+    p8s-addon: storage-provisioner
+spec:
+  hostNetwork: true
+  containers:
+  - name: storage-provisioner
+    image: gcr.io/k8s-minikube/storage-provisioner:v1.8.1
+    command: ["/storage-provisioner"]
+    volumeMounts:
+    - name: tmp
+      mountPath: /tmp
+  volumes:
+  - name: tmp
+    hostPath:
+      path: /tmp/p8s-storage-provisioner
+      type: DirectoryOrCreate
+`
+
+const storageProvisionerStorageClassTemplate = `apiVersion: storage.k8s.io/v1
+kind: StorageClass
+metadata:
+  name: standard
+  annotations:
+    storageclass.kubernetes.io/is-default-class: "true"
+provisioner: k8s.io/minikube-hostpath
+`
+
+const metricsServerTemplate = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: metrics-server
+  namespace: kube-system
+  labels:
+    k8s-app: metrics-server
+    p8s-addon: metrics-server
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      k8s-app: metrics-server
+  template:
+    metadata:
+      labels:
+        k8s-app: metrics-server
+    spec:
+      containers:
+      - name: metrics-server
+        image: k8s.gcr.io/metrics-server-amd64:v0.3.1
+        args:
+        - --kubelet-insecure-tls
+        - --kubelet-preferred-address-types=InternalIP
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: metrics-server
+  namespace: kube-system
+  labels:
+    k8s-app: metrics-server
+spec:
+  selector:
+    k8s-app: metrics-server
+  ports:
+  - port: 443
+    targetPort: 443
+`
+
+const corednsOverrideTemplate = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: coredns
+  namespace: kube-system
+  labels:
+    p8s-addon: coredns-override
+data:
+  Corefile: |
+    .:53 {
+        errors
+        health
+        kubernetes cluster.local in-addr.arpa ip6.arpa {
+          pods insecure
+          upstream
+          fallthrough in-addr.arpa ip6.arpa
+        }
+        forward . /etc/resolv.conf
+        cache 30
+        loop
+        reload
+        loadbalance
+    }
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: kube-dns
+  namespace: kube-system
+  labels:
+    k8s-app: kube-dns
+    p8s-addon: coredns-override
+spec:
+  clusterIP: {{ .DNSClusterIP }}
+  ports:
+  - name: dns
+    port: 53
+    protocol: UDP
+  - name: dns-tcp
+    port: 53
+    protocol: TCP
+  selector:
+    k8s-app: coredns
+`
@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package addons
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"text/template"
+
+	"github.com/golang/glog"
+)
+
+// Manager renders addon manifests against a metadata struct and writes them
+// either into the kubelet static-pod directory or into an on-disk queue
+// consumed once the apiserver is reachable.
+type Manager struct {
+	templatesABSPath  string
+	staticPodABSPath  string
+	applyQueueABSPath string
+	deleteApplied     func(manifest []byte) error
+
+	enabled map[string]bool
+}
+
+// NewManager creates a Manager. templatesABSPath holds the addons' bundled
+// manifest.yaml.tmpl assets, staticPodABSPath is the kubelet static-pod
+// directory and applyQueueABSPath is where ModeApply manifests are staged
+// until the apiserver is ready to receive them. deleteApplied is called with
+// the last-applied manifest bytes of a ModeApply resource when its addon is
+// disabled, so the live API object is removed; the Manager itself has no
+// clientSet, only the Environment constructing it does.
+func NewManager(templatesABSPath, staticPodABSPath, applyQueueABSPath string, enabledNames []string, deleteApplied func(manifest []byte) error) *Manager {
+	enabled := make(map[string]bool, len(enabledNames))
+	for _, name := range enabledNames {
+		enabled[name] = true
+	}
+	return &Manager{
+		templatesABSPath:  templatesABSPath,
+		staticPodABSPath:  staticPodABSPath,
+		applyQueueABSPath: applyQueueABSPath,
+		deleteApplied:     deleteApplied,
+		enabled:           enabled,
+	}
+}
+
+// Enabled returns the names of the addons currently enabled.
+func (m *Manager) Enabled() []string {
+	names := make([]string, 0, len(m.enabled))
+	for name, on := range m.enabled {
+		if on {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// SetupEnabled renders and writes every addon already marked enabled. It's
+// called once during Environment.Setup().
+func (m *Manager) SetupEnabled(data interface{}) error {
+	for name, on := range m.enabled {
+		if !on {
+			continue
+		}
+		if err := m.Enable(name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Enable renders every resource of the addon and delivers it according to
+// its Mode, then marks the addon as enabled.
+func (m *Manager) Enable(name string, data interface{}) error {
+	addon, ok := Get(name)
+	if !ok {
+		err := fmt.Errorf("unknown addon %q", name)
+		glog.Errorf("%v", err)
+		return err
+	}
+	for _, resource := range addon.Resources {
+		manifest, err := m.render(resource, data)
+		if err != nil {
+			glog.Errorf("Cannot render addon %s (%s): %v", name, resource.FileName, err)
+			return err
+		}
+		switch resource.Mode {
+		case ModeStaticPod:
+			err = ioutil.WriteFile(path.Join(m.staticPodABSPath, resource.FileName), manifest, 0644)
+		case ModeApply:
+			err = ioutil.WriteFile(path.Join(m.applyQueueABSPath, resource.FileName), manifest, 0644)
+		default:
+			err = fmt.Errorf("unsupported addon mode %q for %s", resource.Mode, name)
+		}
+		if err != nil {
+			glog.Errorf("Cannot enable addon %s (%s): %v", name, resource.FileName, err)
+			return err
+		}
+	}
+	m.enabled[name] = true
+	glog.V(2).Infof("Addon %s enabled", name)
+	return nil
+}
+
+// Disable removes every previously rendered resource of the addon, from
+// the static-pod directory and/or the apply queue.
+func (m *Manager) Disable(name string) error {
+	addon, ok := Get(name)
+	if !ok {
+		err := fmt.Errorf("unknown addon %q", name)
+		glog.Errorf("%v", err)
+		return err
+	}
+	for _, resource := range addon.Resources {
+		var manifestPath string
+		switch resource.Mode {
+		case ModeStaticPod:
+			manifestPath = path.Join(m.staticPodABSPath, resource.FileName)
+		case ModeApply:
+			manifestPath = path.Join(m.applyQueueABSPath, resource.FileName)
+		}
+		if manifestPath == "" {
+			continue
+		}
+		if resource.Mode == ModeApply && m.deleteApplied != nil {
+			manifest, err := ioutil.ReadFile(manifestPath)
+			if err != nil && !os.IsNotExist(err) {
+				glog.Errorf("Cannot read applied manifest %s to disable addon %s: %v", manifestPath, name, err)
+				return err
+			}
+			if err == nil {
+				if err := m.deleteApplied(manifest); err != nil {
+					glog.Errorf("Cannot delete applied object of addon %s (%s): %v", name, resource.FileName, err)
+					return err
+				}
+			}
+		}
+		if err := os.Remove(manifestPath); err != nil && !os.IsNotExist(err) {
+			glog.Errorf("Cannot disable addon %s (%s): %v", name, resource.FileName, err)
+			return err
+		}
+	}
+	delete(m.enabled, name)
+	glog.V(2).Infof("Addon %s disabled", name)
+	return nil
+}
+
+// PendingApply lists the rendered manifest paths of every ModeApply resource
+// in the apply queue. Manifests stay in the queue after they're applied
+// through the API, so Disable can later delete the objects they describe.
+func (m *Manager) PendingApply() ([]string, error) {
+	entries, err := ioutil.ReadDir(m.applyQueueABSPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		paths = append(paths, path.Join(m.applyQueueABSPath, entry.Name()))
+	}
+	return paths, nil
+}
+
+func (m *Manager) render(resource Resource, data interface{}) ([]byte, error) {
+	tmplPath := path.Join(m.templatesABSPath, resource.TemplateRelPath)
+	tmpl, err := template.ParseFiles(tmplPath)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
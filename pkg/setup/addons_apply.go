@@ -0,0 +1,207 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package setup
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/golang/glog"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// setupApplyAddons applies every addons.ModeApply manifest staged in the
+// apply queue against e.clientSet, now that the apiserver is reachable.
+func (e *Environment) setupApplyAddons() error {
+	manifestPaths, err := e.addonsManager.PendingApply()
+	if err != nil {
+		glog.Errorf("Cannot list pending addon manifests: %v", err)
+		return err
+	}
+	for _, manifestPath := range manifestPaths {
+		if err := e.applyManifestFile(manifestPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyManifestFile applies every YAML document found in manifestPath.
+func (e *Environment) applyManifestFile(manifestPath string) error {
+	content, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		glog.Errorf("Cannot read addon manifest %s: %v", manifestPath, err)
+		return err
+	}
+	if err := e.applyManifest(content); err != nil {
+		glog.Errorf("Cannot apply %s: %v", manifestPath, err)
+		return err
+	}
+	return nil
+}
+
+// applyManifest applies every YAML document found in manifest.
+func (e *Environment) applyManifest(manifest []byte) error {
+	for _, doc := range strings.Split(string(manifest), "\n---\n") {
+		doc = strings.TrimSpace(doc)
+		if doc == "" {
+			continue
+		}
+		if err := e.applyManifestDoc([]byte(doc)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyManifestDoc creates the single Kubernetes object described by doc,
+// and falls back to a get-then-update when it already exists, so an addon
+// like coredns-override actually replaces the cluster's existing object
+// instead of leaving it untouched.
+func (e *Environment) applyManifestDoc(doc []byte) error {
+	var typeMeta metav1.TypeMeta
+	if err := yaml.Unmarshal(doc, &typeMeta); err != nil {
+		return err
+	}
+
+	switch typeMeta.Kind {
+	case "Deployment":
+		var o appsv1.Deployment
+		if err := yaml.Unmarshal(doc, &o); err != nil {
+			return err
+		}
+		client := e.clientSet.AppsV1().Deployments(o.Namespace)
+		if _, err := client.Create(&o); err != nil {
+			if !apierrors.IsAlreadyExists(err) {
+				return err
+			}
+			existing, err := client.Get(o.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			o.ResourceVersion = existing.ResourceVersion
+			if _, err := client.Update(&o); err != nil {
+				return err
+			}
+		}
+	case "Service":
+		var o corev1.Service
+		if err := yaml.Unmarshal(doc, &o); err != nil {
+			return err
+		}
+		client := e.clientSet.CoreV1().Services(o.Namespace)
+		if _, err := client.Create(&o); err != nil {
+			if !apierrors.IsAlreadyExists(err) {
+				return err
+			}
+			existing, err := client.Get(o.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			o.ResourceVersion = existing.ResourceVersion
+			o.Spec.ClusterIP = existing.Spec.ClusterIP
+			if _, err := client.Update(&o); err != nil {
+				return err
+			}
+		}
+	case "ConfigMap":
+		var o corev1.ConfigMap
+		if err := yaml.Unmarshal(doc, &o); err != nil {
+			return err
+		}
+		client := e.clientSet.CoreV1().ConfigMaps(o.Namespace)
+		if _, err := client.Create(&o); err != nil {
+			if !apierrors.IsAlreadyExists(err) {
+				return err
+			}
+			existing, err := client.Get(o.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			o.ResourceVersion = existing.ResourceVersion
+			if _, err := client.Update(&o); err != nil {
+				return err
+			}
+		}
+	case "StorageClass":
+		var o storagev1.StorageClass
+		if err := yaml.Unmarshal(doc, &o); err != nil {
+			return err
+		}
+		client := e.clientSet.StorageV1().StorageClasses()
+		if _, err := client.Create(&o); err != nil {
+			if !apierrors.IsAlreadyExists(err) {
+				return err
+			}
+			existing, err := client.Get(o.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			o.ResourceVersion = existing.ResourceVersion
+			if _, err := client.Update(&o); err != nil {
+				return err
+			}
+		}
+	default:
+		glog.Warningf("Addon object kind %q isn't supported by the apply queue, skipping", typeMeta.Kind)
+	}
+	return nil
+}
+
+// deleteManifestDoc removes every Kubernetes object described in manifest
+// from the API server, tolerating objects already gone. It's handed to
+// addons.NewManager so DisableAddon can clean up a ModeApply resource's
+// live object, not just its staged manifest file.
+func (e *Environment) deleteManifestDoc(manifest []byte) error {
+	for _, doc := range strings.Split(string(manifest), "\n---\n") {
+		doc = strings.TrimSpace(doc)
+		if doc == "" {
+			continue
+		}
+		if err := e.deleteManifestObject([]byte(doc)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Environment) deleteManifestObject(doc []byte) error {
+	var typeMeta metav1.TypeMeta
+	if err := yaml.Unmarshal(doc, &typeMeta); err != nil {
+		return err
+	}
+
+	var meta struct {
+		Metadata metav1.ObjectMeta `json:"metadata"`
+	}
+	if err := yaml.Unmarshal(doc, &meta); err != nil {
+		return err
+	}
+
+	var err error
+	switch typeMeta.Kind {
+	case "Deployment":
+		err = e.clientSet.AppsV1().Deployments(meta.Metadata.Namespace).Delete(meta.Metadata.Name, &metav1.DeleteOptions{})
+	case "Service":
+		err = e.clientSet.CoreV1().Services(meta.Metadata.Namespace).Delete(meta.Metadata.Name, &metav1.DeleteOptions{})
+	case "ConfigMap":
+		err = e.clientSet.CoreV1().ConfigMaps(meta.Metadata.Namespace).Delete(meta.Metadata.Name, &metav1.DeleteOptions{})
+	case "StorageClass":
+		err = e.clientSet.StorageV1().StorageClasses().Delete(meta.Metadata.Name, &metav1.DeleteOptions{})
+	default:
+		glog.Warningf("Addon object kind %q isn't supported by the apply queue, skipping delete", typeMeta.Kind)
+		return nil
+	}
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
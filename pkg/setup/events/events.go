@@ -0,0 +1,39 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package events publishes structured progress of the Setup pipeline so
+// wrappers/CI can consume a stable, machine-readable stream instead of
+// scraping glog lines.
+package events
+
+// Phase describes where a Step currently stands.
+type Phase string
+
+const (
+	// PhaseStart is published right before a step runs
+	PhaseStart Phase = "start"
+	// PhaseProgress is published for incremental progress within a step,
+	// e.g. a download percentage
+	PhaseProgress Phase = "progress"
+	// PhaseDone is published once a step completed successfully
+	PhaseDone Phase = "done"
+	// PhaseError is published when a step failed
+	PhaseError Phase = "error"
+)
+
+// Event is a single, typed progress update of the Setup pipeline.
+type Event struct {
+	Step      string  `json:"step"`
+	Phase     Phase   `json:"phase"`
+	Component string  `json:"component,omitempty"`
+	Message   string  `json:"message,omitempty"`
+	Percent   float64 `json:"percent,omitempty"`
+	Err       string  `json:"error,omitempty"`
+}
+
+// Sink receives Setup pipeline events.
+type Sink interface {
+	Publish(Event)
+}
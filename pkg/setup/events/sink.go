@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/golang/glog"
+)
+
+// GlogSink publishes events the way Setup already reports progress today.
+type GlogSink struct{}
+
+// Publish implements Sink.
+func (GlogSink) Publish(e Event) {
+	switch e.Phase {
+	case PhaseError:
+		glog.Errorf("%s: %s (%s)", e.Step, e.Message, e.Err)
+	case PhaseProgress:
+		glog.V(4).Infof("%s[%s]: %s (%.1f%%)", e.Step, e.Component, e.Message, e.Percent)
+	default:
+		glog.V(3).Infof("%s: %s", e.Step, e.Message)
+	}
+}
+
+// JSONSink publishes one NDJSON-encoded Event per line to w.
+type JSONSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONSink writes NDJSON events to w, e.g. os.Stdout.
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(w)}
+}
+
+// NewJSONSocketSink dials the unix socket at socketPath and streams NDJSON
+// events to it, so a supervisor process can follow Setup progress without
+// inheriting stdout.
+func NewJSONSocketSink(socketPath string) (*JSONSink, error) {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONSink(conn), nil
+}
+
+// Publish implements Sink.
+func (s *JSONSink) Publish(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.enc.Encode(e); err != nil {
+		glog.Warningf("Cannot publish event %s/%s: %v", e.Step, e.Phase, err)
+	}
+}
@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package setup
+
+import (
+	"github.com/golang/glog"
+	"github.com/spf13/pflag"
+
+	"github.com/DataDog/pupernetes/pkg/config"
+)
+
+// RegisterFlags declares the CLI flags backing the image-repository,
+// binary-mirror, addons, nodes, arch, output and event-socket viper keys
+// NewConfigSetup reads, so they're reachable as e.g. --image-repository=...
+// and --binary-mirror=hyperkube=https://... instead of only through a config
+// file, and binds each of them to config.ViperConfig. Callers only need to
+// call flags.Parse(os.Args[1:]) before constructing the Environment.
+func RegisterFlags(flags *pflag.FlagSet) {
+	flags.String("image-repository", "", "Repository prefix used to rewrite the hyperkube image URL, e.g. a private mirror of gcr.io/google_containers")
+	flags.StringToString("binary-mirror", nil, "Per-component mirror for downloaded binaries (hyperkube, etcd, containerd, crio, runc, cni, vault), as component=url or a {version}-templated URL")
+	flags.StringSlice("addons", nil, "Addons to enable at setup time, e.g. dashboard,storage-provisioner,metrics-server,coredns-override")
+	flags.Int("nodes", 0, "Number of additional systemd-nspawn worker kubelets to provision alongside the primary node")
+	flags.String("arch", "", "Target architecture for downloaded binaries, defaults to the host's runtime.GOARCH")
+	flags.String("output", "glog", "Setup pipeline event output: glog or json")
+	flags.String("event-socket", "", "Unix socket path to stream JSON setup events to, in place of the --output sink")
+
+	for _, name := range []string{"image-repository", "binary-mirror", "addons", "nodes", "arch", "output", "event-socket"} {
+		if err := config.ViperConfig.BindPFlag(name, flags.Lookup(name)); err != nil {
+			glog.Errorf("Cannot bind flag --%s: %v", name, err)
+		}
+	}
+}
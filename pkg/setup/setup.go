@@ -8,11 +8,16 @@ package setup
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/coreos/go-systemd/dbus"
@@ -24,6 +29,8 @@ import (
 
 	"github.com/DataDog/pupernetes/pkg/config"
 	"github.com/DataDog/pupernetes/pkg/options"
+	"github.com/DataDog/pupernetes/pkg/setup/addons"
+	"github.com/DataDog/pupernetes/pkg/setup/events"
 	"github.com/DataDog/pupernetes/pkg/setup/requirements"
 	defaultTemplates "github.com/DataDog/pupernetes/pkg/setup/templates"
 	"github.com/DataDog/pupernetes/pkg/util"
@@ -40,6 +47,7 @@ const (
 	defaultSecretDirName          = "secrets"
 	defaultNetworkDirName         = "net.d"
 	defaultLogsDirName            = "logs"
+	defaultAddonsApplyQueueDir    = "addons-apply-queue"
 
 	defaultKubectlClusterName = "p8s"
 	defaultKubectlUserName    = "p8s"
@@ -129,6 +137,30 @@ type Environment struct {
 
 	// CRI
 	containerRuntimeInterface string
+
+	// workers holds one entry per additional kubelet started in its own
+	// systemd-nspawn container when --nodes > 0
+	workers []*workerNode
+
+	// arch is the target CPU architecture of the downloaded binaries,
+	// defaulting to runtime.GOARCH and overridable with --arch
+	arch string
+
+	// eventSink receives structured progress of the Setup pipeline
+	eventSink events.Sink
+
+	// imageRepository overrides the gcr.io/google_containers prefix used
+	// to build the hyperkube image URL
+	imageRepository string
+	// binaryMirror maps a component name (hyperkube, etcd, containerd,
+	// crio, runc, cni, vault) to either a {version}-templated URL or a
+	// base URL used to rewrite the host of the upstream archiveURL
+	binaryMirror map[string]string
+
+	// addonsApplyQueueABSPath stages rendered manifests of addons.ModeApply
+	// addons until the apiserver is ready to receive them
+	addonsApplyQueueABSPath string
+	addonsManager           *addons.Manager
 }
 
 type templateMetadata struct {
@@ -144,6 +176,7 @@ type templateMetadata struct {
 	CgroupDriver             string  `json:"cgroup-driver"`
 	ContainerRuntime         string  `json:"container-runtime"`
 	ContainerRuntimeEndpoint string  `json:"container-runtime-endpoint"`
+	Arch                     string  `json:"arch"`
 }
 
 // NewConfigSetup creates an Environment
@@ -175,6 +208,7 @@ func NewConfigSetup(givenRootPath string) (*Environment, error) {
 		networkConfigABSPath:     path.Join(rootABSPath, defaultNetworkDirName),
 		networkStateABSPath:      path.Join(rootABSPath, "networks"),
 		logsABSPath:              path.Join(rootABSPath, defaultLogsDirName),
+		addonsApplyQueueABSPath:  path.Join(rootABSPath, defaultAddonsApplyQueueDir),
 		templateVersion:          getMajorMinorVersion(config.ViperConfig.GetString("hyperkube-version")),
 
 		kubeConfigUserPath:     config.ViperConfig.GetString("kubeconfig-path"),
@@ -192,13 +226,29 @@ func NewConfigSetup(givenRootPath string) (*Environment, error) {
 		kubeletUnitName:           config.ViperConfig.GetString("systemd-unit-prefix") + "kubelet.service",
 		kubeAPIServerUnitName:     config.ViperConfig.GetString("systemd-unit-prefix") + "kube-apiserver.service",
 		containerRuntimeInterface: config.ViperConfig.GetString("container-runtime"),
+
+		imageRepository: config.ViperConfig.GetString("image-repository"),
+		binaryMirror:    config.ViperConfig.GetStringMapString("binary-mirror"),
+
+		arch: config.ViperConfig.GetString("arch"),
 	}
+	if e.arch == "" {
+		e.arch = runtime.GOARCH
+	}
+	// The archives below aren't checksum-verified after download or
+	// extraction: no per-(component,version,arch) sha256 is recorded.
 	// Kubernetes
+	hyperkubeURL, err := e.resolveBinaryMirror("hyperkube", config.ViperConfig.GetString("hyperkube-version"),
+		fmt.Sprintf("https://dl.k8s.io/v%s/kubernetes-server-linux-%s.tar.gz", config.ViperConfig.GetString("hyperkube-version"), e.arch))
+	if err != nil {
+		glog.Errorf("Cannot resolve binary-mirror for hyperkube: %v", err)
+		return nil, err
+	}
 	e.binaryHyperkube = &exeBinary{
 		depBinary: depBinary{
 			archivePath:     path.Join(e.binABSPath, fmt.Sprintf("hyperkube-v%s.tar.gz", config.ViperConfig.GetString("hyperkube-version"))),
 			binaryABSPath:   path.Join(e.binABSPath, "hyperkube"),
-			archiveURL:      fmt.Sprintf("https://dl.k8s.io/v%s/kubernetes-server-linux-amd64.tar.gz", config.ViperConfig.GetString("hyperkube-version")),
+			archiveURL:      hyperkubeURL,
 			version:         config.ViperConfig.GetString("hyperkube-version"),
 			downloadTimeout: e.downloadTimeout,
 		},
@@ -207,11 +257,17 @@ func NewConfigSetup(givenRootPath string) (*Environment, error) {
 	}
 
 	// Vault
+	vaultURL, err := e.resolveBinaryMirror("vault", config.ViperConfig.GetString("vault-version"),
+		fmt.Sprintf("https://releases.hashicorp.com/vault/%s/vault_%s_linux_%s.zip", config.ViperConfig.GetString("vault-version"), config.ViperConfig.GetString("vault-version"), e.arch))
+	if err != nil {
+		glog.Errorf("Cannot resolve binary-mirror for vault: %v", err)
+		return nil, err
+	}
 	e.binaryVault = &exeBinary{
 		depBinary: depBinary{
 			archivePath:     path.Join(e.binABSPath, fmt.Sprintf("vault-v%s.zip", config.ViperConfig.GetString("vault-version"))),
 			binaryABSPath:   path.Join(e.binABSPath, "vault"),
-			archiveURL:      fmt.Sprintf("https://releases.hashicorp.com/vault/%s/vault_%s_linux_amd64.zip", config.ViperConfig.GetString("vault-version"), config.ViperConfig.GetString("vault-version")),
+			archiveURL:      vaultURL,
 			version:         config.ViperConfig.GetString("vault-version"),
 			downloadTimeout: e.downloadTimeout,
 		},
@@ -220,11 +276,17 @@ func NewConfigSetup(givenRootPath string) (*Environment, error) {
 	}
 
 	// Etcd
+	etcdURL, err := e.resolveBinaryMirror("etcd", config.ViperConfig.GetString("etcd-version"),
+		fmt.Sprintf("https://github.com/etcd-io/etcd/releases/download/v%s/etcd-v%s-linux-%s.tar.gz", config.ViperConfig.GetString("etcd-version"), config.ViperConfig.GetString("etcd-version"), e.arch))
+	if err != nil {
+		glog.Errorf("Cannot resolve binary-mirror for etcd: %v", err)
+		return nil, err
+	}
 	e.binaryEtcd = &exeBinary{
 		depBinary: depBinary{
 			archivePath:     path.Join(e.binABSPath, fmt.Sprintf("etcd-v%s.tar.gz", config.ViperConfig.GetString("etcd-version"))),
 			binaryABSPath:   path.Join(e.binABSPath, "etcd"),
-			archiveURL:      fmt.Sprintf("https://github.com/etcd-io/etcd/releases/download/v%s/etcd-v%s-linux-amd64.tar.gz", config.ViperConfig.GetString("etcd-version"), config.ViperConfig.GetString("etcd-version")),
+			archiveURL:      etcdURL,
 			version:         config.ViperConfig.GetString("etcd-version"),
 			downloadTimeout: e.downloadTimeout,
 		},
@@ -233,11 +295,17 @@ func NewConfigSetup(givenRootPath string) (*Environment, error) {
 	}
 
 	// Containerd
+	containerdURL, err := e.resolveBinaryMirror("containerd", config.ViperConfig.GetString("containerd-version"),
+		fmt.Sprintf("https://github.com/containerd/containerd/releases/download/v%s/containerd-%s.linux-%s.tar.gz", config.ViperConfig.GetString("containerd-version"), config.ViperConfig.GetString("containerd-version"), e.arch))
+	if err != nil {
+		glog.Errorf("Cannot resolve binary-mirror for containerd: %v", err)
+		return nil, err
+	}
 	e.binaryContainerd = &exeBinary{
 		depBinary: depBinary{
 			archivePath:     path.Join(e.binABSPath, fmt.Sprintf("containerd-v%s.tar.gz", config.ViperConfig.GetString("containerd-version"))),
 			binaryABSPath:   path.Join(e.binABSPath, "containerd"),
-			archiveURL:      fmt.Sprintf("https://github.com/containerd/containerd/releases/download/v%s/containerd-%s.linux-amd64.tar.gz", config.ViperConfig.GetString("containerd-version"), config.ViperConfig.GetString("containerd-version")),
+			archiveURL:      containerdURL,
 			version:         config.ViperConfig.GetString("containerd-version"),
 			downloadTimeout: e.downloadTimeout,
 		},
@@ -246,11 +314,17 @@ func NewConfigSetup(givenRootPath string) (*Environment, error) {
 	}
 
 	// CRI-o
+	crioURL, err := e.resolveBinaryMirror("crio", config.ViperConfig.GetString("crio-version"),
+		fmt.Sprintf("https://launchpad.net/~projectatomic/+archive/ubuntu/ppa/+files/cri-o-1.11-stable_%s-1~ubuntu18.04~ppa3_amd64.deb", config.ViperConfig.GetString("crio-version")))
+	if err != nil {
+		glog.Errorf("Cannot resolve binary-mirror for crio: %v", err)
+		return nil, err
+	}
 	e.binaryCrio = &exeBinary{
 		depBinary: depBinary{
 			archivePath:     path.Join(e.binABSPath, fmt.Sprintf("crio-v%s.deb", config.ViperConfig.GetString("crio-version"))),
 			binaryABSPath:   path.Join(e.binABSPath, "crio"),
-			archiveURL:      fmt.Sprintf("https://launchpad.net/~projectatomic/+archive/ubuntu/ppa/+files/cri-o-1.11-stable_%s-1~ubuntu18.04~ppa3_amd64.deb", config.ViperConfig.GetString("crio-version")),
+			archiveURL:      crioURL,
 			version:         config.ViperConfig.GetString("crio-version"),
 			downloadTimeout: e.downloadTimeout,
 		},
@@ -258,11 +332,17 @@ func NewConfigSetup(givenRootPath string) (*Environment, error) {
 	}
 
 	// Runc
+	runcURL, err := e.resolveBinaryMirror("runc", config.ViperConfig.GetString("runc-version"),
+		fmt.Sprintf("https://github.com/opencontainers/runc/releases/download/v%s/runc.%s", config.ViperConfig.GetString("runc-version"), e.arch))
+	if err != nil {
+		glog.Errorf("Cannot resolve binary-mirror for runc: %v", err)
+		return nil, err
+	}
 	e.binaryRunc = &exeBinary{
 		depBinary: depBinary{
 			archivePath:     path.Join(e.binABSPath, fmt.Sprintf("runc-v%s", config.ViperConfig.GetString("runc-version"))),
 			binaryABSPath:   path.Join(e.binABSPath, "runc"),
-			archiveURL:      fmt.Sprintf("https://github.com/opencontainers/runc/releases/download/v%s/runc.amd64", config.ViperConfig.GetString("runc-version")),
+			archiveURL:      runcURL,
 			version:         config.ViperConfig.GetString("runc-version"),
 			downloadTimeout: e.downloadTimeout,
 		},
@@ -271,10 +351,16 @@ func NewConfigSetup(givenRootPath string) (*Environment, error) {
 	}
 
 	// CNI
+	cniURL, err := e.resolveBinaryMirror("cni", config.ViperConfig.GetString("cni-version"),
+		fmt.Sprintf("https://github.com/containernetworking/plugins/releases/download/v%s/cni-plugins-%s-v%s.tgz", config.ViperConfig.GetString("cni-version"), e.arch, config.ViperConfig.GetString("cni-version")))
+	if err != nil {
+		glog.Errorf("Cannot resolve binary-mirror for cni: %v", err)
+		return nil, err
+	}
 	e.binaryCNI = &depBinary{
 		archivePath:     path.Join(e.binABSPath, fmt.Sprintf("cni-v%s.tar.gz", config.ViperConfig.GetString("cni-version"))),
 		binaryABSPath:   path.Join(e.binABSPath, "bridge"),
-		archiveURL:      fmt.Sprintf("https://github.com/containernetworking/plugins/releases/download/v%s/cni-plugins-amd64-v%s.tgz", config.ViperConfig.GetString("cni-version"), config.ViperConfig.GetString("cni-version")),
+		archiveURL:      cniURL,
 		version:         config.ViperConfig.GetString("cni-version"),
 		downloadTimeout: e.downloadTimeout,
 	}
@@ -309,6 +395,31 @@ func NewConfigSetup(givenRootPath string) (*Environment, error) {
 		return nil, err
 	}
 
+	// Multi-node: carve podCIDR between the primary node and every worker
+	if nodes := config.ViperConfig.GetInt("nodes"); nodes > 0 {
+		subnets, err := splitCIDR(e.podCIDR, nodes+1)
+		if err != nil {
+			glog.Errorf("Cannot carve pod sub-CIDRs for %d worker node(s): %v", nodes, err)
+			return nil, err
+		}
+		e.podCIDR = subnets[0]
+		for i := 1; i <= nodes; i++ {
+			name := fmt.Sprintf("worker-%d", i)
+			w := &workerNode{
+				index:           i,
+				name:            name,
+				nspawnUnitName:  fmt.Sprintf("%snspawn-%s.service", e.systemdUnitPrefix, name),
+				kubeletUnitName: fmt.Sprintf("%skubelet-%s.service", e.systemdUnitPrefix, name),
+				rootfsABSPath:   path.Join(rootABSPath, "workers", name, "rootfs"),
+				kubeletRootDir:  path.Join(e.kubeletRootDir, name),
+				criLogABSPath:   path.Join(KubeletCRILogPath, name),
+				podSubCIDR:      subnets[i],
+			}
+			e.workers = append(e.workers, w)
+			e.systemdUnitNames = append(e.systemdUnitNames, w.nspawnUnitName, w.kubeletUnitName)
+		}
+	}
+
 	// kubeconfig
 	if e.kubeConfigUserPath == "" {
 		e.kubeConfigUserPath = path.Join(getHome(), ".kube", "config")
@@ -341,10 +452,19 @@ func NewConfigSetup(givenRootPath string) (*Environment, error) {
 		cgroupDriver = info.CgroupDriver
 	}
 
+	imageRepository := "gcr.io/google_containers"
+	if e.imageRepository != "" {
+		imageRepository = e.imageRepository
+	}
+
+	hyperkubeImageURL := fmt.Sprintf("%s/hyperkube:v%s", imageRepository, e.binaryHyperkube.version)
+	if e.arch != "amd64" {
+		hyperkubeImageURL = fmt.Sprintf("%s/hyperkube:v%s-%s", imageRepository, e.binaryHyperkube.version, e.arch)
+	}
+
 	// Template for manifests
 	e.templateMetadata = &templateMetadata{
-		// TODO conf this
-		HyperkubeImageURL:        fmt.Sprintf("gcr.io/google_containers/hyperkube:v%s", e.binaryHyperkube.version),
+		HyperkubeImageURL:        hyperkubeImageURL,
 		Hostname:                 &e.hostname,
 		RootABSPath:              &e.rootABSPath,
 		ServiceClusterIPRange:    e.kubernetesClusterCIDR.String(),
@@ -354,6 +474,7 @@ func NewConfigSetup(givenRootPath string) (*Environment, error) {
 		ContainerRuntime:         containerRuntime,
 		ContainerRuntimeEndpoint: ContainerRuntimeEndpoint,
 		CgroupDriver:             cgroupDriver,
+		Arch:                     e.arch,
 		NodeIP:                   &e.nodeIP, // initialized later
 	}
 
@@ -363,9 +484,176 @@ func NewConfigSetup(givenRootPath string) (*Environment, error) {
 		e.vaultRootToken = util.RandStringBytesMaskImprSrc(20)
 		glog.V(4).Infof("Generated the vault root-token of length: %d", len(e.vaultRootToken))
 	}
+
+	e.eventSink = events.GlogSink{}
+	if config.ViperConfig.GetString("output") == "json" {
+		e.eventSink = events.NewJSONSink(os.Stdout)
+	}
+	if socketPath := config.ViperConfig.GetString("event-socket"); socketPath != "" {
+		sink, err := events.NewJSONSocketSink(socketPath)
+		if err != nil {
+			glog.Warningf("Cannot dial --event-socket %s, keeping the --output sink: %v", socketPath, err)
+		} else {
+			e.eventSink = sink
+		}
+	}
+
+	e.addonsManager = addons.NewManager(
+		path.Join(e.manifestTemplatesABSPath, "addons"),
+		e.manifestStaticPodABSPath,
+		e.addonsApplyQueueABSPath,
+		config.ViperConfig.GetStringSlice("addons"),
+		e.deleteManifestDoc,
+	)
 	return e, nil
 }
 
+// setupWorkers provisions the systemd-nspawn rootfs skeleton of every
+// worker node declared through --nodes, bind-mounts the hyperkube/runc
+// binaries downloaded for the primary node into it instead of
+// re-downloading them, and renders the nspawn/kubelet systemd units that
+// createEnd2EndSection/dbusClient later start from e.systemdUnitNames.
+func (e *Environment) setupWorkers() error {
+	for _, w := range e.workers {
+		for _, dir := range []string{w.rootfsABSPath, w.kubeletRootDir, w.criLogABSPath, w.rootfsBinABSPath()} {
+			glog.V(4).Infof("Creating directory: %s", dir)
+			if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+				glog.Errorf("Cannot create %s for worker %s: %v", dir, w.name, err)
+				return err
+			}
+		}
+
+		err := syscall.Mount(e.binABSPath, w.rootfsBinABSPath(), "", syscall.MS_BIND|syscall.MS_RDONLY, "")
+		if err != nil && err != syscall.EBUSY {
+			glog.Errorf("Cannot bind-mount %s into %s for worker %s: %v", e.binABSPath, w.rootfsBinABSPath(), w.name, err)
+			return err
+		}
+		if err == nil {
+			// MS_RDONLY is ignored on the initial bind, a remount applies it
+			err = syscall.Mount("", w.rootfsBinABSPath(), "", syscall.MS_REMOUNT|syscall.MS_BIND|syscall.MS_RDONLY, "")
+			if err != nil {
+				glog.Errorf("Cannot remount %s read-only for worker %s: %v", w.rootfsBinABSPath(), w.name, err)
+				return err
+			}
+		}
+
+		units := map[string][]*unit.UnitOption{
+			w.nspawnUnitName:  nspawnUnitOptions(e, w),
+			w.kubeletUnitName: kubeletWorkerUnitOptions(e, w),
+		}
+		for name, opts := range units {
+			unitPath := path.Join(e.manifestSystemdUnit, name)
+			f, err := os.Create(unitPath)
+			if err != nil {
+				glog.Errorf("Cannot create unit file %s for worker %s: %v", unitPath, w.name, err)
+				return err
+			}
+			_, err = io.Copy(f, unit.Serialize(opts))
+			closeErr := f.Close()
+			if err != nil {
+				glog.Errorf("Cannot write unit file %s for worker %s: %v", unitPath, w.name, err)
+				return err
+			}
+			if closeErr != nil {
+				glog.Errorf("Cannot close unit file %s for worker %s: %v", unitPath, w.name, closeErr)
+				return closeErr
+			}
+		}
+
+		glog.V(2).Infof("Worker %s ready, podSubCIDR=%s kubelet=%s nspawn=%s",
+			w.name, w.podSubCIDR, w.kubeletUnitName, w.nspawnUnitName)
+	}
+	return nil
+}
+
+// setupAddons materializes the bundled addon templates and renders every
+// addon currently enabled through --addons.
+func (e *Environment) setupAddons() error {
+	if err := addons.WriteSourceTemplates(path.Join(e.manifestTemplatesABSPath, "addons")); err != nil {
+		glog.Errorf("Cannot write addon templates: %v", err)
+		return err
+	}
+	if err := e.addonsManager.SetupEnabled(e.templateMetadata); err != nil {
+		glog.Errorf("Cannot setup enabled addons: %v", err)
+		return err
+	}
+	return nil
+}
+
+// EnableAddon renders and enables the addon identified by name, writing its
+// manifest into the static-pod directory or into the apply queue.
+func (e *Environment) EnableAddon(name string) error {
+	return e.addonsManager.Enable(name, e.templateMetadata)
+}
+
+// DisableAddon removes a previously enabled addon's rendered manifest.
+func (e *Environment) DisableAddon(name string) error {
+	return e.addonsManager.Disable(name)
+}
+
+// supportedArches lists the CPU architectures p8s knows how to resolve
+// upstream binary/image URLs for.
+var supportedArches = []string{"amd64", "arm64"}
+
+// checkArchSupported rejects e.arch early, before any download is attempted.
+func (e *Environment) checkArchSupported() error {
+	supported := false
+	for _, a := range supportedArches {
+		if e.arch == a {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		err := fmt.Errorf("unsupported architecture %q, supported: %v", e.arch, supportedArches)
+		glog.Errorf("%v", err)
+		return err
+	}
+	// CRI-O is only published as an amd64 .deb by its launchpad PPA, unlike
+	// the other binaries which have per-arch upstream archives.
+	if e.containerRuntimeInterface == config.CRICrio && e.arch != "amd64" {
+		err := fmt.Errorf("container-runtime %q is only available for amd64, got arch %q", config.CRICrio, e.arch)
+		glog.Errorf("%v", err)
+		return err
+	}
+	return nil
+}
+
+// resolveBinaryMirror rewrites upstreamURL for component according to the
+// --binary-mirror configuration. A mirror value containing a "{version}"
+// placeholder is used verbatim as a URL template, otherwise it's treated as
+// a base URL and only its scheme/host/path-prefix are spliced onto
+// upstreamURL. With no mirror configured for component, upstreamURL is
+// returned unchanged. A mirror that can't be parsed into a usable
+// scheme+host is a configuration error and is reported rather than silently
+// dropped.
+func (e *Environment) resolveBinaryMirror(component, version, upstreamURL string) (string, error) {
+	mirror, ok := e.binaryMirror[component]
+	if !ok || mirror == "" {
+		return upstreamURL, nil
+	}
+	if strings.Contains(mirror, "{version}") {
+		return strings.Replace(mirror, "{version}", version, -1), nil
+	}
+	mirrorURL, err := url.Parse(mirror)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse --binary-mirror value %q for %s: %v", mirror, component, err)
+	}
+	if mirrorURL.Scheme == "" || mirrorURL.Host == "" {
+		return "", fmt.Errorf("--binary-mirror value %q for %s is missing a scheme and/or host, expected e.g. \"https://%s\"", mirror, component, mirror)
+	}
+	u, err := url.Parse(upstreamURL)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse upstream URL %q for %s: %v", upstreamURL, component, err)
+	}
+	u.Scheme = mirrorURL.Scheme
+	u.Host = mirrorURL.Host
+	if mirrorURL.Path != "" && mirrorURL.Path != "/" {
+		u.Path = path.Join(mirrorURL.Path, u.Path)
+	}
+	return u.String(), nil
+}
+
 func (e *Environment) setupDirectories() error {
 	for _, dir := range []string{
 		e.binABSPath,
@@ -384,6 +672,8 @@ func (e *Environment) setupDirectories() error {
 		e.kubeletRootDir,
 		KubeletCRILogPath,
 		e.logsABSPath,
+		path.Join(e.manifestTemplatesABSPath, "addons"),
+		e.addonsApplyQueueABSPath,
 	} {
 		glog.V(4).Infof("Creating directory: %s", dir)
 		err := os.MkdirAll(dir, os.ModePerm)
@@ -396,32 +686,87 @@ func (e *Environment) setupDirectories() error {
 	return nil
 }
 
+// setupStep names a Setup() pipeline function so it can be reported through
+// e.eventSink. component is set for binary download steps, so their start
+// and completion can also be published as download-progress events.
+type setupStep struct {
+	name      string
+	component string
+	fn        func() error
+}
+
 // Setup the Environment
 func (e *Environment) Setup() error {
-	var err error
 	glog.V(3).Infof("Setup starting %s", e.rootABSPath)
-	for _, f := range []func() error{
-		requirements.CheckRequirements,
-		e.setupHostname,
-		e.setupDirectories,
-		e.setupBinaryCNI,
-		e.setupBinaryEtcd,
-		e.setupBinaryContainerd,
-		e.setupBinaryCrio,
-		e.setupBinaryRunc,
-		e.setupBinaryVault,
-		e.setupBinaryHyperkube,
-		e.setupNetwork,
-		e.setupManifests,
-		e.setupSystemd,
-		e.setupSecrets,
-		e.setupKubeClients,
+	for _, s := range []setupStep{
+		{name: "check-arch", fn: e.checkArchSupported},
+		{name: "check-requirements", fn: requirements.CheckRequirements},
+		{name: "hostname", fn: e.setupHostname},
+		{name: "directories", fn: e.setupDirectories},
+		{name: "binary-cni", component: "cni", fn: e.setupBinaryCNI},
+		{name: "binary-etcd", component: "etcd", fn: e.setupBinaryEtcd},
+		{name: "binary-containerd", component: "containerd", fn: e.setupBinaryContainerd},
+		{name: "binary-crio", component: "crio", fn: e.setupBinaryCrio},
+		{name: "binary-runc", component: "runc", fn: e.setupBinaryRunc},
+		{name: "binary-vault", component: "vault", fn: e.setupBinaryVault},
+		{name: "binary-hyperkube", component: "hyperkube", fn: e.setupBinaryHyperkube},
+		{name: "network", fn: e.setupNetwork},
+		{name: "workers", fn: e.setupWorkers},
+		{name: "manifests", fn: e.setupManifests},
+		{name: "addons", fn: e.setupAddons},
+		{name: "systemd", fn: e.setupSystemd},
+		{name: "secrets", fn: e.setupSecrets},
+		{name: "kube-clients", fn: e.setupKubeClients},
+		{name: "apply-addons", fn: e.setupApplyAddons},
 	} {
-		err = f()
-		if err != nil {
+		e.publishEvent(s.name, events.PhaseStart, "", fmt.Sprintf("%s starting", s.name), nil)
+		// Only coarse 0%/100% download-progress events are emitted, around
+		// each binary-* step: the depBinary/exeBinary byte-transfer loop
+		// doesn't publish per-byte percentages.
+		if s.component != "" {
+			e.PublishDownloadProgress(s.component, 0)
+		}
+		if err := s.fn(); err != nil {
+			e.publishEvent(s.name, events.PhaseError, "", "", err)
 			return err
 		}
+		if s.component != "" {
+			e.PublishDownloadProgress(s.component, 100)
+		}
+		e.publishEvent(s.name, events.PhaseDone, "", fmt.Sprintf("%s ready", s.name), nil)
 	}
+	e.publishEvent("setup", events.PhaseDone, "", fmt.Sprintf(
+		"setup ready: cri=%s podCIDR=%s serviceCIDR=%s units=%v",
+		e.containerRuntimeInterface, e.podCIDR, e.kubernetesClusterCIDR, e.systemdUnitNames), nil)
 	glog.V(2).Infof("Setup ready %s", e.rootABSPath)
 	return nil
 }
+
+// PublishDownloadProgress reports the download progress of component as a
+// PhaseProgress event, so depBinary/exeBinary fetch code can surface
+// per-component percentages without scraping glog lines. It's only called
+// at the start/end of each binary-* Setup step (0/100); the depBinary/exeBinary
+// fetch loop doesn't call it.
+func (e *Environment) PublishDownloadProgress(component string, percent float64) {
+	if e.eventSink == nil {
+		return
+	}
+	e.eventSink.Publish(events.Event{
+		Step:      "download",
+		Phase:     events.PhaseProgress,
+		Component: component,
+		Percent:   percent,
+	})
+}
+
+// publishEvent forwards a structured progress update to e.eventSink, if any.
+func (e *Environment) publishEvent(step string, phase events.Phase, component, message string, err error) {
+	if e.eventSink == nil {
+		return
+	}
+	ev := events.Event{Step: step, Phase: phase, Component: component, Message: message}
+	if err != nil {
+		ev.Err = err.Error()
+	}
+	e.eventSink.Publish(ev)
+}
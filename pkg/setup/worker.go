@@ -0,0 +1,120 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package setup
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"path"
+
+	"github.com/coreos/go-systemd/unit"
+)
+
+// defaultWorkerBridgeName is the bridge interface worker containers are
+// attached to, so they reach the primary node's apiserver over the host
+// network the way a bare-metal kubelet would.
+const defaultWorkerBridgeName = "p8s-workers0"
+
+// workerNode describes an additional kubelet running inside its own
+// systemd-nspawn container, attached through a veth pair to the host bridge
+// defaultWorkerBridgeName and joining the same apiserver as the primary node.
+type workerNode struct {
+	index int
+	name  string
+
+	nspawnUnitName  string
+	kubeletUnitName string
+
+	rootfsABSPath  string
+	kubeletRootDir string
+	criLogABSPath  string
+
+	podSubCIDR *net.IPNet
+}
+
+// splitCIDR carves n equally sized, contiguous subnets from the start of
+// parent, splitting it into the smallest power of two of blocks that is at
+// least n; blocks beyond the first n are left unused. It only supports IPv4
+// and fails if parent doesn't have enough address space.
+func splitCIDR(parent *net.IPNet, n int) ([]*net.IPNet, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("cannot split %s into %d subnets", parent, n)
+	}
+	ip4 := parent.IP.To4()
+	if ip4 == nil {
+		return nil, fmt.Errorf("splitCIDR only supports IPv4, got %s", parent)
+	}
+	ones, bits := parent.Mask.Size()
+	extraBits := 0
+	for (1 << uint(extraBits)) < n {
+		extraBits++
+	}
+	newOnes := ones + extraBits
+	if newOnes > bits {
+		return nil, fmt.Errorf("%s doesn't have enough address space for %d subnets", parent, n)
+	}
+
+	base := binary.BigEndian.Uint32(ip4)
+	blockSize := uint32(1) << uint(bits-newOnes)
+	subnets := make([]*net.IPNet, 0, n)
+	for i := 0; i < n; i++ {
+		subnetIP := make(net.IP, 4)
+		binary.BigEndian.PutUint32(subnetIP, base+uint32(i)*blockSize)
+		subnets = append(subnets, &net.IPNet{
+			IP:   subnetIP,
+			Mask: net.CIDRMask(newOnes, bits),
+		})
+	}
+	return subnets, nil
+}
+
+// rootfsBinABSPath is where the primary node's downloaded hyperkube/runc
+// binaries are bind-mounted read-only inside a worker's rootfs.
+func (w *workerNode) rootfsBinABSPath() string {
+	return path.Join(w.rootfsABSPath, "opt", "p8s", "bin")
+}
+
+// nspawnUnitOptions describes the systemd-nspawn container that hosts
+// worker w, bridged onto the host network and bind-mounting its kubelet
+// root dir. The shared binaries are already in its rootfs, bind-mounted
+// there by setupWorkers.
+func nspawnUnitOptions(e *Environment, w *workerNode) []*unit.UnitOption {
+	execStart := fmt.Sprintf(
+		"/usr/bin/systemd-nspawn --quiet --keep-unit --boot "+
+			"--machine=%s --directory=%s "+
+			"--bind=%s:%s "+
+			"--network-bridge=%s",
+		w.name, w.rootfsABSPath, w.kubeletRootDir, w.kubeletRootDir, defaultWorkerBridgeName)
+	return []*unit.UnitOption{
+		{Section: "Unit", Name: "Description", Value: fmt.Sprintf("p8s worker node %s (systemd-nspawn)", w.name)},
+		{Section: "Service", Name: "ExecStart", Value: execStart},
+		{Section: "Service", Name: "Restart", Value: "on-failure"},
+	}
+}
+
+// kubeletWorkerUnitOptions describes the kubelet running inside w's
+// nspawn container, using the binaries bind-mounted by setupWorkers
+// and the auth kubeconfig already produced for the primary node. The flag
+// set mirrors what the primary node's kubelet manifest passes, so the
+// worker is a realistic second node rather than a bare stub.
+func kubeletWorkerUnitOptions(e *Environment, w *workerNode) []*unit.UnitOption {
+	execStart := fmt.Sprintf(
+		"%s kubelet "+
+			"--root-dir=%s --pod-cidr=%s --kubeconfig=%s "+
+			"--container-runtime=%s --container-runtime-endpoint=%s --cgroup-driver=%s "+
+			"--network-plugin=cni --cni-conf-dir=%s --cni-bin-dir=%s",
+		path.Join(w.rootfsBinABSPath(), "hyperkube"),
+		w.kubeletRootDir, w.podSubCIDR, e.kubeConfigAuthPath,
+		e.templateMetadata.ContainerRuntime, e.templateMetadata.ContainerRuntimeEndpoint, e.templateMetadata.CgroupDriver,
+		e.networkConfigABSPath, e.binABSPath)
+	return []*unit.UnitOption{
+		{Section: "Unit", Name: "Description", Value: fmt.Sprintf("p8s kubelet for worker %s", w.name)},
+		{Section: "Unit", Name: "JoinsNamespaceOf", Value: w.nspawnUnitName},
+		{Section: "Service", Name: "ExecStart", Value: execStart},
+		{Section: "Service", Name: "Restart", Value: "on-failure"},
+	}
+}